@@ -39,33 +39,12 @@ func SaveVAPIDKeysJSON(filename string, keys *webpush.VAPIDKeys) error {
 	return os.WriteFile(filename, j, 0600)
 }
 
-// LoadVAPIDKeysJSON reads Web-push–style JSON and reconstructs VAPIDKeys.
-func LoadVAPIDKeysJSON(filename string) (*webpush.VAPIDKeys, error) {
-	b, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-	vk := new(webpush.VAPIDKeys)
-	if err := json.Unmarshal(b, vk); err != nil {
-		return nil, err
-	}
-	return vk, nil
-}
-
-func LoadVAPIDKeysPEM(filename string) (*webpush.VAPIDKeys, error) {
-	b, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-	return webpush.LoadVAPIDPrivateKeyPEM(b)
-}
-
 func main() {
-	vk1, err := LoadVAPIDKeysPEM("vapid_private.pem")
+	vk1, err := webpush.LoadVAPIDKeysFile("vapid_private.pem")
 	if err != nil {
 		log.Printf("could not load VAPID keys from PEM: %v\n", err)
 	}
-	vk2, err := LoadVAPIDKeysJSON("vapid_keys.json")
+	vk2, err := webpush.LoadVAPIDKeysFile("vapid_keys.json")
 	if err != nil {
 		log.Printf("could not load VAPID keys from JSON: %v\n", err)
 	}
@@ -73,6 +52,7 @@ func main() {
 	if vk1 != nil && vk2 != nil && vk1.Equal(vk2) {
 		log.Println("VAPID keys are equal")
 		log.Println("Using loaded keys")
+		vapidKeys = vk1
 	} else {
 		log.Println("Generating new VAPID keys")
 		var err error
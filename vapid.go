@@ -1,9 +1,11 @@
 package webpush
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -11,6 +13,7 @@ import (
 	"fmt"
 	"math/big"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -21,6 +24,14 @@ import (
 type VAPIDKeys struct {
 	privateKey *ecdsa.PrivateKey
 	publicKey  string // raw bytes encoding in urlsafe base64, as per RFC
+	includeKID bool   // include the RFC 7638 thumbprint as "kid" in the JWT header
+}
+
+// SetIncludeKID controls whether getVAPIDAuthorizationHeader includes this
+// key's thumbprint (see Thumbprint) as the "kid" header of the signed JWT.
+// Disabled by default for backward compatibility with existing deployments.
+func (v *VAPIDKeys) SetIncludeKID(include bool) {
+	v.includeKID = include
 }
 
 // PublicKeyString returns the base64url-encoded uncompressed public key of the keypair, as defined in RFC8292.
@@ -38,6 +49,25 @@ func (v *VAPIDKeys) Equal(o *VAPIDKeys) bool {
 	return v.privateKey.Equal(o.privateKey)
 }
 
+// Thumbprint returns the RFC 7638 JWK Thumbprint of the public key: the
+// base64url-unpadded SHA-256 digest of the canonical JSON
+// {"crv":"P-256","kty":"EC","x":"<b64url>","y":"<b64url>"}, with members in
+// lexicographic order and no whitespace. The result is a stable, portable
+// key identifier that survives PEM/JSON round-trips.
+func (v *VAPIDKeys) Thumbprint() string {
+	x, _ := base64URLBigInt(v.privateKey.X, 32)
+	y, _ := base64URLBigInt(v.privateKey.Y, 32)
+	canonical := fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, x, y)
+	digest := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// KeyID returns the same value as Thumbprint, as a convenience "kid" for
+// indexing VAPIDKeys in a store or correlating them across logs.
+func (v *VAPIDKeys) KeyID() string {
+	return v.Thumbprint()
+}
+
 // MarshalJSON implements json.Marshaler producing Web-push–style JSON:
 //
 //	{"publicKey":"<base64url>", "privateKey":"<base64url>"}
@@ -108,6 +138,127 @@ func (v *VAPIDKeys) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// jwkEC is the JSON Web Key (RFC 7517/7518) representation of a P-256 EC key.
+// D is omitted for public-only keys.
+type jwkEC struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+}
+
+// base64URLBigInt encodes i as a base64url-unpadded string, left-padded with
+// zero bytes to size. This matches the fixed-width encoding RFC 7518 requires
+// for P-256 coordinates and scalars (32 bytes).
+func base64URLBigInt(i *big.Int, size int) (string, error) {
+	b := i.Bytes()
+	if len(b) > size {
+		return "", fmt.Errorf("invalid integer size: %d", len(b))
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return base64.RawURLEncoding.EncodeToString(padded), nil
+}
+
+// MarshalJWK encodes the keypair as an RFC 7517/7518 JSON Web Key:
+//
+//	{"kty":"EC","crv":"P-256","x":"<b64url>","y":"<b64url>","d":"<b64url>"}
+//
+// Coordinates and the private scalar are base64url-unpadded, left-padded to
+// 32 bytes. Use MarshalJWKPublic to omit the private scalar.
+func (v *VAPIDKeys) MarshalJWK() ([]byte, error) {
+	if v == nil || v.privateKey == nil {
+		return nil, fmt.Errorf("vapid keys are nil")
+	}
+	x, err := base64URLBigInt(v.privateKey.X, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+	y, err := base64URLBigInt(v.privateKey.Y, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+	d, err := base64URLBigInt(v.privateKey.D, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid d scalar: %w", err)
+	}
+	return json.Marshal(jwkEC{Kty: "EC", Crv: "P-256", X: x, Y: y, D: d})
+}
+
+// MarshalJWKPublic encodes the public key alone as an RFC 7517/7518 JSON Web
+// Key, omitting "d" so it can be published to clients or a .well-known
+// endpoint.
+func (v *VAPIDKeys) MarshalJWKPublic() ([]byte, error) {
+	if v == nil || v.privateKey == nil {
+		return nil, fmt.Errorf("vapid keys are nil")
+	}
+	x, err := base64URLBigInt(v.privateKey.X, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+	y, err := base64URLBigInt(v.privateKey.Y, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+	return json.Marshal(jwkEC{Kty: "EC", Crv: "P-256", X: x, Y: y})
+}
+
+// UnmarshalJWK parses an RFC 7517/7518 JSON Web Key produced by MarshalJWK.
+// It validates that kty is "EC", crv is "P-256", that x/y land on the P-256
+// curve, and that d*G == (x,y).
+func (v *VAPIDKeys) UnmarshalJWK(b []byte) error {
+	var jwk jwkEC
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return err
+	}
+	if jwk.Kty != "EC" {
+		return fmt.Errorf("unsupported JWK kty: %q", jwk.Kty)
+	}
+	if jwk.Crv != "P-256" {
+		return fmt.Errorf("unsupported JWK crv: %q", jwk.Crv)
+	}
+	if jwk.D == "" {
+		return fmt.Errorf("JWK is missing private scalar d")
+	}
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return fmt.Errorf("invalid x encoding: %w", err)
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return fmt.Errorf("invalid y encoding: %w", err)
+	}
+	dBytes, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	if err != nil {
+		return fmt.Errorf("invalid d encoding: %w", err)
+	}
+
+	curve := elliptic.P256()
+	x := new(big.Int).SetBytes(xBytes)
+	y := new(big.Int).SetBytes(yBytes)
+	if !curve.IsOnCurve(x, y) {
+		return fmt.Errorf("JWK x/y do not lie on P-256")
+	}
+
+	d := new(big.Int).SetBytes(dBytes)
+	checkX, checkY := curve.ScalarBaseMult(dBytes)
+	if checkX.Cmp(x) != 0 || checkY.Cmp(y) != 0 {
+		return fmt.Errorf("JWK d does not correspond to x/y")
+	}
+
+	v.privateKey = &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         d,
+	}
+	pubStr, err := makePublicKeyString(v.privateKey)
+	if err != nil {
+		return err
+	}
+	v.publicKey = pubStr
+	return nil
+}
+
 // GenerateVAPIDKeys generates a VAPID keypair (an ECDSA keypair on the P-256 curve).
 func GenerateVAPIDKeys() (result *VAPIDKeys, err error) {
 	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -158,6 +309,25 @@ func getVAPIDAuthorizationHeader(
 	subscriber string,
 	vapidKeys *VAPIDKeys,
 	expiration time.Time,
+) (string, error) {
+	kid := ""
+	if vapidKeys.includeKID {
+		kid = vapidKeys.KeyID()
+	}
+	return signVAPIDAuthorizationHeader(endpoint, subscriber, vapidKeys, expiration, kid)
+}
+
+// signVAPIDAuthorizationHeader is getVAPIDAuthorizationHeader with an
+// explicit kid, so callers that already know which kid to sign with (e.g.
+// VAPIDKeyManager) don't need to mutate the shared VAPIDKeys.includeKID flag
+// to get it into the JWT header. An empty kid omits the header, matching
+// VAPIDKeys.includeKID being disabled.
+func signVAPIDAuthorizationHeader(
+	endpoint string,
+	subscriber string,
+	vapidKeys *VAPIDKeys,
+	expiration time.Time,
+	kid string,
 ) (string, error) {
 	if expiration.IsZero() {
 		expiration = time.Now().Add(time.Hour * 12)
@@ -179,6 +349,9 @@ func getVAPIDAuthorizationHeader(
 		"exp": expiration.Unix(),
 		"sub": subscriber,
 	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
 
 	jwtString, err := token.SignedString(vapidKeys.privateKey)
 	if err != nil {
@@ -212,6 +385,9 @@ func LoadVAPIDPrivateKeyPEM(pemBytes []byte) (*VAPIDKeys, error) {
 	if pemBlock == nil {
 		return nil, fmt.Errorf("could not decode PEM block with VAPID keys")
 	}
+	if pemBlock.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, ErrVAPIDKeyEncrypted
+	}
 	privKey, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
 	if err != nil {
 		return nil, err
@@ -229,3 +405,68 @@ func LoadVAPIDPrivateKeyPEM(pemBytes []byte) (*VAPIDKeys, error) {
 	}
 	return &VAPIDKeys{privateKey: privateKey, publicKey: pub}, nil
 }
+
+// LoadVAPIDKeys sniffs data and decodes it as VAPIDKeys, trying in turn: a
+// PKCS#8 PEM block, a Web-push–style JSON object
+// ({"publicKey":"...","privateKey":"..."}), an RFC 7517/7518 JWK
+// ({"kty":"EC",...}), and finally a bare base64url-unpadded 32-byte private
+// scalar as produced by the Node.js `web-push generate-vapid-keys` CLI. This
+// lets servers migrate existing key material into this module without a
+// conversion step.
+func LoadVAPIDKeys(data []byte) (*VAPIDKeys, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("vapid: empty key data")
+	}
+
+	if block, _ := pem.Decode(trimmed); block != nil {
+		return LoadVAPIDPrivateKeyPEM(trimmed)
+	}
+
+	if trimmed[0] == '{' {
+		var probe struct {
+			Kty string `json:"kty"`
+		}
+		if err := json.Unmarshal(trimmed, &probe); err != nil {
+			return nil, fmt.Errorf("vapid: could not parse JSON key data: %w", err)
+		}
+		keys := new(VAPIDKeys)
+		if probe.Kty != "" {
+			if err := keys.UnmarshalJWK(trimmed); err != nil {
+				return nil, err
+			}
+			return keys, nil
+		}
+		if err := json.Unmarshal(trimmed, keys); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+
+	// Bare base64url-unpadded 32-byte scalar, as emitted by the Node.js
+	// web-push CLI.
+	scalar, err := base64.RawURLEncoding.DecodeString(string(trimmed))
+	if err != nil || len(scalar) != 32 {
+		return nil, fmt.Errorf("vapid: unrecognized key format")
+	}
+	curve := elliptic.P256()
+	priv := new(ecdsa.PrivateKey)
+	priv.Curve = curve
+	priv.D = new(big.Int).SetBytes(scalar)
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(scalar)
+
+	pubStr, err := makePublicKeyString(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{privateKey: priv, publicKey: pubStr}, nil
+}
+
+// LoadVAPIDKeysFile reads path and decodes it with LoadVAPIDKeys.
+func LoadVAPIDKeysFile(path string) (*VAPIDKeys, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("vapid: could not read %q: %w", path, err)
+	}
+	return LoadVAPIDKeys(b)
+}
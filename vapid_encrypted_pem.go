@@ -0,0 +1,334 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrVAPIDKeyEncrypted is returned by LoadVAPIDPrivateKeyPEM when it is given
+// a PKCS#8 "ENCRYPTED PRIVATE KEY" block. Callers should use
+// LoadVAPIDPrivateKeyPEMEncrypted with the matching passphrase instead.
+var ErrVAPIDKeyEncrypted = errors.New("vapid: private key is encrypted, use LoadVAPIDPrivateKeyPEMEncrypted")
+
+// PBES2Cipher selects the symmetric cipher used to protect an encrypted
+// PKCS#8 private key.
+type PBES2Cipher int
+
+const (
+	// AES256CBC encrypts with AES-256 in CBC mode with PKCS#7 padding.
+	AES256CBC PBES2Cipher = iota
+	// AES256GCM encrypts with AES-256 in GCM mode, adding authentication.
+	AES256GCM
+)
+
+// defaultPBES2Iterations follows current OWASP/NIST guidance for
+// PBKDF2-HMAC-SHA256 (at least 600,000 iterations as of 2023).
+const defaultPBES2Iterations = 600_000
+
+const defaultPBES2SaltLength = 16
+
+// EncOption configures ExportVAPIDPrivateKeyPEMEncrypted.
+type EncOption func(*pbes2Options)
+
+type pbes2Options struct {
+	iterations int
+	saltLength int
+	cipher     PBES2Cipher
+}
+
+// WithPBES2Iterations overrides the PBKDF2 iteration count. The default is
+// 600,000.
+func WithPBES2Iterations(iterations int) EncOption {
+	return func(o *pbes2Options) { o.iterations = iterations }
+}
+
+// WithPBES2SaltLength overrides the PBKDF2 salt length in bytes. The default
+// is 16.
+func WithPBES2SaltLength(length int) EncOption {
+	return func(o *pbes2Options) { o.saltLength = length }
+}
+
+// WithPBES2Cipher overrides the symmetric cipher. The default is AES256CBC.
+func WithPBES2Cipher(c PBES2Cipher) EncOption {
+	return func(o *pbes2Options) { o.cipher = c }
+}
+
+// PKCS#5/PKCS#8 (RFC 8018, RFC 5084) object identifiers.
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES256GCM      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+)
+
+var asn1NULL = asn1.RawValue{FullBytes: []byte{0x05, 0x00}}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type gcmParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// ExportVAPIDPrivateKeyPEMEncrypted writes the private key as a PKCS#8
+// EncryptedPrivateKeyInfo PEM block ("ENCRYPTED PRIVATE KEY"), protected with
+// PBES2 (PBKDF2-HMAC-SHA256 plus AES-256-CBC or AES-256-GCM, see
+// WithPBES2Cipher). The default iteration count is 600,000, in line with
+// current PBKDF2 guidance.
+func (v *VAPIDKeys) ExportVAPIDPrivateKeyPEMEncrypted(passphrase []byte, opts ...EncOption) ([]byte, error) {
+	if v == nil || v.privateKey == nil {
+		return nil, fmt.Errorf("vapid keys are nil")
+	}
+
+	cfg := pbes2Options{
+		iterations: defaultPBES2Iterations,
+		saltLength: defaultPBES2SaltLength,
+		cipher:     AES256CBC,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	plaintext, err := x509.MarshalPKCS8PrivateKey(v.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal VAPID keys to PKCS#8: %w", err)
+	}
+
+	salt := make([]byte, cfg.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("could not generate PBES2 salt: %w", err)
+	}
+	key := pbkdf2.Key(passphrase, salt, cfg.iterations, 32, sha256.New)
+
+	var encryptionScheme pkix.AlgorithmIdentifier
+	var ciphertext []byte
+	switch cfg.cipher {
+	case AES256CBC:
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, fmt.Errorf("could not generate IV: %w", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		padded := pkcs7Pad(plaintext, aes.BlockSize)
+		ciphertext = make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+		ivDER, err := asn1.Marshal(iv)
+		if err != nil {
+			return nil, err
+		}
+		encryptionScheme = pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivDER}}
+	case AES256GCM:
+		nonce := make([]byte, 12)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("could not generate nonce: %w", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext = gcm.Seal(nil, nonce, plaintext, nil)
+
+		// cipher.NewGCM defaults to a 16-byte authentication tag (not the
+		// GCMParameters default of 12), and that tag is appended to
+		// ciphertext by Seal. The advertised aes-ICVlen must match it, or a
+		// standards-compliant parser mis-frames the ciphertext.
+		paramsDER, err := asn1.Marshal(gcmParams{Nonce: nonce, ICVLen: gcm.Overhead()})
+		if err != nil {
+			return nil, err
+		}
+		encryptionScheme = pkix.AlgorithmIdentifier{Algorithm: oidAES256GCM, Parameters: asn1.RawValue{FullBytes: paramsDER}}
+	default:
+		return nil, fmt.Errorf("unsupported PBES2 cipher: %d", cfg.cipher)
+	}
+
+	kdfParamsDER, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: cfg.iterations,
+		KeyLength:      32,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1NULL},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pbes2ParamsDER, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParamsDER}},
+		EncryptionScheme:  encryptionScheme,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo:          pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: pbes2ParamsDER}},
+		EncryptedData: ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der})
+	if pemBytes == nil {
+		return nil, fmt.Errorf("could not encode VAPID keys as PEM")
+	}
+	return pemBytes, nil
+}
+
+// LoadVAPIDPrivateKeyPEMEncrypted reads a PKCS#8 EncryptedPrivateKeyInfo PEM
+// block produced by ExportVAPIDPrivateKeyPEMEncrypted and decrypts it with
+// passphrase.
+func LoadVAPIDPrivateKeyPEMEncrypted(pemBytes []byte, passphrase []byte) (*VAPIDKeys, error) {
+	pemBlock, _ := pem.Decode(pemBytes)
+	if pemBlock == nil {
+		return nil, fmt.Errorf("could not decode PEM block with VAPID keys")
+	}
+	if pemBlock.Type != "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("unexpected PEM block type %q, want \"ENCRYPTED PRIVATE KEY\"", pemBlock.Type)
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(pemBlock.Bytes, &info); err != nil {
+		return nil, fmt.Errorf("could not parse EncryptedPrivateKeyInfo: %w", err)
+	}
+	if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption algorithm %s, only PBES2 is supported", info.Algo.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("could not parse PBES2-params: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("could not parse PBKDF2-params: %w", err)
+	}
+	keyLength := kdf.KeyLength
+	if keyLength == 0 {
+		keyLength = 32
+	}
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keyLength, sha256.New)
+
+	var plaintext []byte
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		var iv []byte
+		if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+			return nil, fmt.Errorf("could not parse AES-CBC IV: %w", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("vapid: incorrect passphrase or corrupt data")
+		}
+		padded := make([]byte, len(info.EncryptedData))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(padded, info.EncryptedData)
+		plaintext, err = pkcs7Unpad(padded, aes.BlockSize)
+		if err != nil {
+			return nil, fmt.Errorf("vapid: incorrect passphrase or corrupt data: %w", err)
+		}
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256GCM):
+		var gp gcmParams
+		if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &gp); err != nil {
+			return nil, fmt.Errorf("could not parse AES-GCM parameters: %w", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = gcm.Open(nil, gp.Nonce, info.EncryptedData, nil)
+		if err != nil {
+			return nil, fmt.Errorf("vapid: incorrect passphrase or corrupt data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme %s", params.EncryptionScheme.Algorithm)
+	}
+
+	privKey, err := x509.ParsePKCS8PrivateKey(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vapid: incorrect passphrase or corrupt data: %w", err)
+	}
+	privateKey, ok := privKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid type of private key %T", privKey)
+	}
+	if privateKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("invalid curve for private key %v", privateKey.Curve)
+	}
+	pub, err := makePublicKeyString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{privateKey: privateKey, publicKey: pub}, nil
+}
+
+func pkcs7Pad(b []byte, blockSize int) []byte {
+	padLen := blockSize - len(b)%blockSize
+	padded := make([]byte, len(b)+padLen)
+	copy(padded, b)
+	for i := len(b); i < len(padded); i++ {
+		padded[i] = byte(padLen)
+	}
+	return padded
+}
+
+func pkcs7Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, fmt.Errorf("invalid padded data length")
+	}
+	padLen := int(b[len(b)-1])
+	if padLen == 0 || padLen > blockSize || padLen > len(b) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	for _, p := range b[len(b)-padLen:] {
+		if int(p) != padLen {
+			return nil, fmt.Errorf("invalid padding")
+		}
+	}
+	return b[:len(b)-padLen], nil
+}
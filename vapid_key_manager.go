@@ -0,0 +1,262 @@
+package webpush
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VAPIDKeyState describes the lifecycle state of a key held by a
+// VAPIDKeyManager.
+type VAPIDKeyState int
+
+const (
+	// VAPIDKeyActive is the single key currently used to sign new VAPID JWTs.
+	VAPIDKeyActive VAPIDKeyState = iota
+	// VAPIDKeyRetiring is still accepted by push services but no longer used
+	// to sign new notifications; kept around during a rotation window.
+	VAPIDKeyRetiring
+	// VAPIDKeyRetired is kept only for bookkeeping and is no longer expected
+	// to be used or trusted.
+	VAPIDKeyRetired
+)
+
+func (s VAPIDKeyState) String() string {
+	switch s {
+	case VAPIDKeyActive:
+		return "active"
+	case VAPIDKeyRetiring:
+		return "retiring"
+	case VAPIDKeyRetired:
+		return "retired"
+	default:
+		return "unknown"
+	}
+}
+
+// vapidJWTCacheSkew is subtracted from a cached JWT's expiration so that the
+// cache refreshes the token shortly before push services would reject it.
+const vapidJWTCacheSkew = 5 * time.Minute
+
+type vapidManagedKey struct {
+	keys  *VAPIDKeys
+	state VAPIDKeyState
+}
+
+type vapidCachedToken struct {
+	header string
+	expiry time.Time
+}
+
+// VAPIDKeyManager holds a set of VAPIDKeys addressed by kid (see
+// VAPIDKeys.KeyID), tracks each key's rotation state, and caches signed VAPID
+// JWTs per (kid, audience-origin, subscriber) so a burst of notifications to
+// the same push service can reuse one signature instead of re-signing per
+// notification. Call AuthorizationHeader to get the header for the active
+// key instead of signing one directly with getVAPIDAuthorizationHeader.
+//
+// Options and SendNotification do not accept a VAPIDKeyManager; headers
+// signed by the manager are obtained only by calling AuthorizationHeader
+// directly.
+type VAPIDKeyManager struct {
+	mu        sync.RWMutex
+	keys      map[string]*vapidManagedKey
+	activeKID string
+	cache     sync.Map // cacheKey -> *vapidCachedToken
+}
+
+// NewVAPIDKeyManager returns an empty VAPIDKeyManager.
+func NewVAPIDKeyManager() *VAPIDKeyManager {
+	return &VAPIDKeyManager{keys: make(map[string]*vapidManagedKey)}
+}
+
+// Add registers keys under its KeyID, returning the kid it was added under.
+// The first key added to an empty manager becomes the active key; every
+// later key is added as VAPIDKeyRetiring until promoted with SetActive.
+func (m *VAPIDKeyManager) Add(keys *VAPIDKeys) string {
+	kid := keys.KeyID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := VAPIDKeyRetiring
+	if len(m.keys) == 0 {
+		state = VAPIDKeyActive
+		m.activeKID = kid
+	}
+	m.keys[kid] = &vapidManagedKey{keys: keys, state: state}
+	return kid
+}
+
+// SetActive promotes the key identified by kid to VAPIDKeyActive, demoting
+// the previously active key (if any) to VAPIDKeyRetiring.
+func (m *VAPIDKeyManager) SetActive(kid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mk, ok := m.keys[kid]
+	if !ok {
+		return fmt.Errorf("vapid key manager: unknown kid %q", kid)
+	}
+	if old, ok := m.keys[m.activeKID]; ok && m.activeKID != kid {
+		old.state = VAPIDKeyRetiring
+	}
+	mk.state = VAPIDKeyActive
+	m.activeKID = kid
+	return nil
+}
+
+// Retire marks the key identified by kid as VAPIDKeyRetired. Retiring the
+// active key leaves the manager with no active key until SetActive is called.
+func (m *VAPIDKeyManager) Retire(kid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mk, ok := m.keys[kid]
+	if !ok {
+		return fmt.Errorf("vapid key manager: unknown kid %q", kid)
+	}
+	mk.state = VAPIDKeyRetired
+	if m.activeKID == kid {
+		m.activeKID = ""
+	}
+	return nil
+}
+
+// Get returns the key registered under kid, if any.
+func (m *VAPIDKeyManager) Get(kid string) (*VAPIDKeys, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mk, ok := m.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	return mk.keys, true
+}
+
+// State returns the rotation state of the key registered under kid.
+func (m *VAPIDKeyManager) State(kid string) (VAPIDKeyState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mk, ok := m.keys[kid]
+	if !ok {
+		return 0, false
+	}
+	return mk.state, true
+}
+
+// Active returns the currently active key, or nil if there is none.
+func (m *VAPIDKeyManager) Active() *VAPIDKeys {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mk, ok := m.keys[m.activeKID]
+	if !ok {
+		return nil
+	}
+	return mk.keys
+}
+
+// LoadDir adds every *.pem and *.jwk file under path to the manager. PEM
+// files are parsed as unencrypted PKCS#8 private keys; JWK files are parsed
+// as RFC 7517/7518 JSON Web Keys. Keys are added with Add, so the first key
+// loaded becomes active only if the manager held no keys before.
+func (m *VAPIDKeyManager) LoadDir(path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("vapid key manager: could not read directory %q: %w", path, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		full := filepath.Join(path, name)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".pem":
+			b, err := os.ReadFile(full)
+			if err != nil {
+				return fmt.Errorf("vapid key manager: could not read %q: %w", full, err)
+			}
+			keys, err := LoadVAPIDPrivateKeyPEM(b)
+			if err != nil {
+				return fmt.Errorf("vapid key manager: could not load %q: %w", full, err)
+			}
+			m.Add(keys)
+		case ".jwk":
+			b, err := os.ReadFile(full)
+			if err != nil {
+				return fmt.Errorf("vapid key manager: could not read %q: %w", full, err)
+			}
+			keys := new(VAPIDKeys)
+			if err := keys.UnmarshalJWK(b); err != nil {
+				return fmt.Errorf("vapid key manager: could not load %q: %w", full, err)
+			}
+			m.Add(keys)
+		}
+	}
+	return nil
+}
+
+// SaveDir writes every key in the manager to path as "<kid>.pem", an
+// unencrypted PKCS#8 PEM file. path must already exist.
+func (m *VAPIDKeyManager) SaveDir(path string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for kid, mk := range m.keys {
+		pemBytes, err := mk.keys.ExportVAPIDPrivateKeyPEM()
+		if err != nil {
+			return fmt.Errorf("vapid key manager: could not export kid %q: %w", kid, err)
+		}
+		full := filepath.Join(path, kid+".pem")
+		if err := os.WriteFile(full, pemBytes, 0600); err != nil {
+			return fmt.Errorf("vapid key manager: could not write %q: %w", full, err)
+		}
+	}
+	return nil
+}
+
+// AuthorizationHeader signs (or reuses a cached) VAPID Authorization header
+// for endpoint/subscriber using the active key, with the active key's kid
+// included in the JWT header. Signed tokens are cached per (kid,
+// audience-origin, subscriber) and reused until shortly before they expire.
+//
+// The kid is passed to the signer explicitly rather than toggled on the
+// shared *VAPIDKeys via SetIncludeKID, since the active key can be read and
+// signed with concurrently by many goroutines fanning out notifications.
+func (m *VAPIDKeyManager) AuthorizationHeader(endpoint, subscriber string) (string, error) {
+	active := m.Active()
+	if active == nil {
+		return "", fmt.Errorf("vapid key manager: no active key")
+	}
+	kid := active.KeyID()
+
+	subURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	origin := subURL.Scheme + "://" + subURL.Host
+	cacheKey := kid + "|" + origin + "|" + subscriber
+
+	if v, ok := m.cache.Load(cacheKey); ok {
+		cached := v.(*vapidCachedToken)
+		if time.Now().Before(cached.expiry) {
+			return cached.header, nil
+		}
+	}
+
+	expiration := time.Now().Add(12 * time.Hour)
+	header, err := signVAPIDAuthorizationHeader(endpoint, subscriber, active, expiration, kid)
+	if err != nil {
+		return "", err
+	}
+	m.cache.Store(cacheKey, &vapidCachedToken{header: header, expiry: expiration.Add(-vapidJWTCacheSkew)})
+	return header, nil
+}
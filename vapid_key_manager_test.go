@@ -0,0 +1,198 @@
+package webpush
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestVAPIDKeyManager_AddActiveGet(t *testing.T) {
+	m := NewVAPIDKeyManager()
+
+	k1, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid1 := m.Add(k1)
+	if m.Active() == nil || !m.Active().Equal(k1) {
+		t.Fatalf("first added key should become active")
+	}
+
+	k2, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid2 := m.Add(k2)
+	if state, ok := m.State(kid2); !ok || state != VAPIDKeyRetiring {
+		t.Fatalf("second key should be added as retiring, got %v", state)
+	}
+
+	if err := m.SetActive(kid2); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Active().Equal(k2) {
+		t.Fatalf("SetActive did not promote kid2")
+	}
+	if state, _ := m.State(kid1); state != VAPIDKeyRetiring {
+		t.Fatalf("previous active key should be demoted to retiring, got %v", state)
+	}
+
+	got, ok := m.Get(kid1)
+	if !ok || !got.Equal(k1) {
+		t.Fatalf("Get did not return the original key for kid1")
+	}
+
+	if err := m.SetActive("missing"); err == nil {
+		t.Fatalf("expected error promoting an unknown kid")
+	}
+}
+
+func TestVAPIDKeyManager_Retire(t *testing.T) {
+	m := NewVAPIDKeyManager()
+	k, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := m.Add(k)
+
+	if err := m.Retire(kid); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := m.State(kid); state != VAPIDKeyRetired {
+		t.Fatalf("expected retired state, got %v", state)
+	}
+	if err := m.Retire("missing"); err == nil {
+		t.Fatalf("expected error retiring an unknown kid")
+	}
+}
+
+func TestVAPIDKeyManager_SaveLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewVAPIDKeyManager()
+	k1, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Add(k1)
+	m.Add(k2)
+
+	if err := m.SaveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewVAPIDKeyManager()
+	if err := loaded.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*VAPIDKeys{k1, k2} {
+		got, ok := loaded.Get(k.KeyID())
+		if !ok || !got.Equal(k) {
+			t.Fatalf("LoadDir did not recover key %s", k.KeyID())
+		}
+	}
+}
+
+func TestVAPIDKeyManager_LoadDirJWK(t *testing.T) {
+	dir := t.TempDir()
+	k, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := k.MarshalJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.jwk"), jwk, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewVAPIDKeyManager()
+	if err := m.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := m.Get(k.KeyID())
+	if !ok || !got.Equal(k) {
+		t.Fatalf("LoadDir did not recover key from JWK file")
+	}
+}
+
+func TestVAPIDKeyManager_AuthorizationHeaderCaching(t *testing.T) {
+	m := NewVAPIDKeyManager()
+	if _, err := m.AuthorizationHeader("https://push.example/v2/token", "user@example.com"); err == nil {
+		t.Fatalf("expected error with no active key")
+	}
+
+	k, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Add(k)
+
+	hdr1, err := m.AuthorizationHeader("https://push.example/v2/token-a", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr2, err := m.AuthorizationHeader("https://push.example/v2/token-b", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr1 != hdr2 {
+		t.Fatalf("expected cached header reuse across endpoints sharing an origin")
+	}
+
+	hdr3, err := m.AuthorizationHeader("https://other.example/v2/token", "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr3 == hdr1 {
+		t.Fatalf("expected distinct header for a different audience origin")
+	}
+
+	hdr4, err := m.AuthorizationHeader("https://push.example/v2/token-a", "other-user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr4 == hdr1 {
+		t.Fatalf("expected distinct header for a different subscriber sharing an origin")
+	}
+}
+
+func TestVAPIDKeyManager_AuthorizationHeaderConcurrent(t *testing.T) {
+	m := NewVAPIDKeyManager()
+	k, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Add(k)
+
+	endpoints := []string{
+		"https://push.example/v2/token",
+		"https://other.example/v2/token",
+		"https://third.example/v2/token",
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(endpoints)*10)
+	for i := 0; i < 10; i++ {
+		for _, endpoint := range endpoints {
+			wg.Add(1)
+			go func(endpoint string) {
+				defer wg.Done()
+				if _, err := m.AuthorizationHeader(endpoint, "user@example.com"); err != nil {
+					errs <- err
+				}
+			}(endpoint)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -206,6 +208,59 @@ func TestVAPID_GetAuthorizationHeader(t *testing.T) {
 	}
 }
 
+func TestVAPID_Thumbprint(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tp := keys.Thumbprint()
+	if tp == "" {
+		t.Fatal("expected non-empty thumbprint")
+	}
+	if tp != keys.KeyID() {
+		t.Fatalf("KeyID should match Thumbprint, got %q vs %q", keys.KeyID(), tp)
+	}
+	if strings.ContainsAny(tp, "+/=") {
+		t.Fatalf("thumbprint should be base64url-unpadded, got %q", tp)
+	}
+
+	// Thumbprint is deterministic and survives a JSON round-trip.
+	j, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := new(VAPIDKeys)
+	if err := json.Unmarshal(j, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Thumbprint() != tp {
+		t.Fatalf("thumbprint did not survive JSON round-trip")
+	}
+}
+
+func TestVAPID_IncludeKID(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.SetIncludeKID(true)
+
+	hdr, err := getVAPIDAuthorizationHeader("https://push.example/v2/token", "user@example.com", keys, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenString := getTokenFromAuthorizationHeader(hdr, t)
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != keys.KeyID() {
+		t.Fatalf("expected kid header %q, got %v", keys.KeyID(), token.Header["kid"])
+	}
+}
+
 func TestVAPID_PEMExportLoad(t *testing.T) {
 	// Nil receiver export
 	var nilKeys *VAPIDKeys
@@ -268,6 +323,213 @@ func TestVAPID_PEMExportLoad(t *testing.T) {
 	})
 }
 
+func TestVAPID_JWKRoundTrip(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, err := keys.MarshalJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := new(VAPIDKeys)
+	if err := loaded.UnmarshalJWK(jwk); err != nil {
+		t.Fatal(err)
+	}
+	if !keys.Equal(loaded) {
+		t.Fatalf("JWK round-trip did not preserve private key")
+	}
+	if keys.publicKey != loaded.publicKey {
+		t.Fatalf("JWK round-trip did not preserve public key")
+	}
+
+	pubJWK, err := keys.MarshalJWKPublic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded jwkEC
+	if err := json.Unmarshal(pubJWK, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.D != "" {
+		t.Fatalf("MarshalJWKPublic should omit d, got %q", decoded.D)
+	}
+	if decoded.Kty != "EC" || decoded.Crv != "P-256" {
+		t.Fatalf("unexpected kty/crv: %q/%q", decoded.Kty, decoded.Crv)
+	}
+}
+
+func TestVAPID_UnmarshalJWKErrors(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := keys.MarshalJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid jwkEC
+	if err := json.Unmarshal(jwk, &valid); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(j jwkEC) jwkEC
+		errSub string
+	}{
+		{
+			name:   "wrongKty",
+			mutate: func(j jwkEC) jwkEC { j.Kty = "RSA"; return j },
+			errSub: "unsupported JWK kty",
+		},
+		{
+			name:   "wrongCrv",
+			mutate: func(j jwkEC) jwkEC { j.Crv = "P-384"; return j },
+			errSub: "unsupported JWK crv",
+		},
+		{
+			name:   "missingD",
+			mutate: func(j jwkEC) jwkEC { j.D = ""; return j },
+			errSub: "missing private scalar",
+		},
+		{
+			name:   "dMismatch",
+			mutate: func(j jwkEC) jwkEC { j.D = valid.X; return j },
+			errSub: "does not correspond to x/y",
+		},
+	}
+	for _, tc := range cases {
+		c := tc
+		t.Run(c.name, func(t *testing.T) {
+			mutated := c.mutate(valid)
+			b, err := json.Marshal(mutated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v VAPIDKeys
+			if err := v.UnmarshalJWK(b); err == nil || !strings.Contains(err.Error(), c.errSub) {
+				t.Fatalf("expected error containing %q, got: %v", c.errSub, err)
+			}
+		})
+	}
+}
+
+func TestVAPID_EncryptedPEMRoundTrip(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphrase := []byte("correct horse battery staple")
+
+	for _, c := range []PBES2Cipher{AES256CBC, AES256GCM} {
+		pemBytes, err := keys.ExportVAPIDPrivateKeyPEMEncrypted(passphrase, WithPBES2Iterations(1000), WithPBES2Cipher(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadVAPIDPrivateKeyPEMEncrypted(pemBytes, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !keys.Equal(loaded) {
+			t.Fatalf("encrypted PEM round-trip did not preserve keys for cipher %d", c)
+		}
+
+		// Loading with an unencrypted loader should fail loudly.
+		if _, err := LoadVAPIDPrivateKeyPEM(pemBytes); !errors.Is(err, ErrVAPIDKeyEncrypted) {
+			t.Fatalf("expected ErrVAPIDKeyEncrypted, got: %v", err)
+		}
+
+		// Wrong passphrase should fail.
+		if _, err := LoadVAPIDPrivateKeyPEMEncrypted(pemBytes, []byte("wrong passphrase")); err == nil {
+			t.Fatalf("expected error decrypting with wrong passphrase")
+		}
+	}
+}
+
+func TestLoadVAPIDKeys(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pemBytes, err := keys.ExportVAPIDPrivateKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonBytes, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwkBytes, err := keys.MarshalJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := keys.PrivateKey().D.Bytes()
+	padded := make([]byte, 32)
+	copy(padded[32-len(d):], d)
+	scalarBytes := []byte(base64.RawURLEncoding.EncodeToString(padded))
+
+	for name, data := range map[string][]byte{
+		"pem":    pemBytes,
+		"json":   jsonBytes,
+		"jwk":    jwkBytes,
+		"scalar": scalarBytes,
+	} {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := LoadVAPIDKeys(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !keys.Equal(loaded) {
+				t.Fatalf("LoadVAPIDKeys did not preserve private key for %s format", name)
+			}
+		})
+	}
+
+	t.Run("emptyInput", func(t *testing.T) {
+		if _, err := LoadVAPIDKeys(nil); err == nil {
+			t.Fatalf("expected error for empty input")
+		}
+	})
+
+	t.Run("unrecognized", func(t *testing.T) {
+		if _, err := LoadVAPIDKeys([]byte("not a key")); err == nil {
+			t.Fatalf("expected error for unrecognized input")
+		}
+	})
+}
+
+func TestLoadVAPIDKeysFile(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := keys.ExportVAPIDPrivateKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := t.TempDir() + "/vapid_private.pem"
+	if err := os.WriteFile(path, pemBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadVAPIDKeysFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !keys.Equal(loaded) {
+		t.Fatalf("LoadVAPIDKeysFile did not preserve private key")
+	}
+
+	if _, err := LoadVAPIDKeysFile(path + ".missing"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
 func TestECDSAToVAPIDKeys_InvalidCurve(t *testing.T) {
 	p384key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	if err != nil {